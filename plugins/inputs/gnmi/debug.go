@@ -0,0 +1,196 @@
+package gnmi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// Summary of a device for the debug index
+type debugTargetSummary struct {
+	Address                string     `json:"address"`
+	Source                 string     `json:"source"`
+	Port                   string     `json:"port"`
+	Connected              bool       `json:"connected"`
+	LastUpdate             *time.Time `json:"last_update,omitempty"`
+	SecondsSinceLastUpdate float64    `json:"seconds_since_last_update"`
+	Stale                  []string   `json:"stale_subscriptions"`
+}
+
+// Detailed state of a single device
+type debugTarget struct {
+	debugTargetSummary
+	Subscriptions    []debugSubscription `json:"subscriptions"`
+	TagSubscriptions []debugSubscription `json:"tag_subscriptions"`
+	Errors           []debugError        `json:"errors"`
+}
+
+type debugSubscription struct {
+	Name           string     `json:"name"`
+	Origin         string     `json:"origin,omitempty"`
+	Path           string     `json:"path"`
+	Mode           string     `json:"mode"`
+	SampleInterval string     `json:"sample_interval,omitempty"`
+	Match          string     `json:"match,omitempty"`
+	Elements       []string   `json:"elements,omitempty"`
+	LastUpdate     *time.Time `json:"last_update,omitempty"`
+	Stale          bool       `json:"stale"`
+}
+
+type debugError struct {
+	Class     string    `json:"class"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func checkDebugAddress(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return errors.New("only loopback addresses are allowed")
+}
+
+func (c *GNMI) startDebugServer() error {
+	listener, err := net.Listen("tcp", c.DebugAddr)
+	if err != nil {
+		return fmt.Errorf("creating debug listener failed: %w", err)
+	}
+	c.debugListener = listener
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /targets", c.serveDebugIndex)
+	mux.HandleFunc("GET /targets/{address}", c.serveDebugTarget)
+	c.debugServer = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if err := c.debugServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			c.Log.Errorf("Serving debug endpoint failed: %v", err)
+		}
+	}()
+	c.Log.Infof("Serving debug endpoint on %s", listener.Addr())
+
+	return nil
+}
+
+func (c *GNMI) serveDebugIndex(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
+	summaries := make([]debugTargetSummary, 0, len(c.targets))
+	for _, target := range c.targets {
+		summaries = append(summaries, c.debugSummary(target, now))
+	}
+	writeDebugJSON(w, summaries)
+}
+
+func (c *GNMI) serveDebugTarget(w http.ResponseWriter, r *http.Request) {
+	address := r.PathValue("address")
+	for _, target := range c.targets {
+		if target.address == address {
+			writeDebugJSON(w, c.debugDetails(target, time.Now()))
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("unknown target %q", address), http.StatusNotFound)
+}
+
+func (c *GNMI) debugSummary(target *targetState, now time.Time) debugTargetSummary {
+	summary := debugTargetSummary{
+		Address:   target.address,
+		Source:    target.host,
+		Port:      target.port,
+		Connected: target.connected.Load(),
+		Stale:     make([]string, 0),
+	}
+	last := c.started
+	if ts := target.lastUpdate.Load(); ts > 0 {
+		last = time.Unix(0, ts)
+		summary.LastUpdate = &last
+	}
+	summary.SecondsSinceLastUpdate = now.Sub(last).Seconds()
+
+	for _, s := range c.Subscriptions {
+		if c.isStale(target, s.Name, now) && !slices.Contains(summary.Stale, s.Name) {
+			summary.Stale = append(summary.Stale, s.Name)
+		}
+	}
+	return summary
+}
+
+func (c *GNMI) debugDetails(target *targetState, now time.Time) debugTarget {
+	details := debugTarget{
+		debugTargetSummary: c.debugSummary(target, now),
+		Subscriptions:      make([]debugSubscription, 0, len(c.Subscriptions)),
+		TagSubscriptions:   make([]debugSubscription, 0, len(c.TagSubscriptions)),
+	}
+	for _, s := range c.Subscriptions {
+		sub := newDebugSubscription(&s)
+		if ts := target.subscriptions[s.Name].Load(); ts > 0 {
+			last := time.Unix(0, ts)
+			sub.LastUpdate = &last
+		}
+		sub.Stale = c.isStale(target, s.Name, now)
+		details.Subscriptions = append(details.Subscriptions, sub)
+	}
+	for _, s := range c.TagSubscriptions {
+		sub := newDebugSubscription(&s.subscription)
+		sub.Match = s.Match
+		sub.Elements = s.Elements
+		details.TagSubscriptions = append(details.TagSubscriptions, sub)
+	}
+	errs := target.recentErrors()
+	details.Errors = make([]debugError, 0, len(errs))
+	for _, e := range errs {
+		details.Errors = append(details.Errors, debugError{Class: e.class, Message: e.message, Timestamp: e.ts})
+	}
+	return details
+}
+
+func (c *GNMI) isStale(target *targetState, name string, now time.Time) bool {
+	threshold, found := c.staleThresholds[name]
+	if !found {
+		return false
+	}
+	last := c.started
+	if ts := target.subscriptions[name].Load(); ts > 0 {
+		last = time.Unix(0, ts)
+	}
+	return now.Sub(last) > threshold
+}
+
+func newDebugSubscription(s *subscription) debugSubscription {
+	sub := debugSubscription{
+		Name:   s.Name,
+		Origin: s.Origin,
+		Path:   s.Path,
+		Mode:   s.SubscriptionMode,
+	}
+	if s.SampleInterval > 0 {
+		sub.SampleInterval = time.Duration(s.SampleInterval).String()
+	}
+	return sub
+}
+
+func writeDebugJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,76 @@
+package gnmi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Classes of errors occurring during the lifetime of a device connection
+const (
+	errorClassDNS         = "dns_failure"
+	errorClassRefused     = "tcp_refused"
+	errorClassTLS         = "tls_handshake"
+	errorClassAuth        = "auth_failed"
+	errorClassRejected    = "subscribe_rejected"
+	errorClassStreamReset = "stream_reset"
+	errorClassDecode      = "decode_error"
+	errorClassTimeout     = "timeout"
+	errorClassUnknown     = "unknown"
+)
+
+// Classify the given error, the fallback class is used if the error cannot
+// be attributed to a more specific class
+func classifyError(err error, fallback string) string {
+	if err == nil {
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return errorClassDNS
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return errorClassTimeout
+	}
+
+	if s, ok := status.FromError(err); ok {
+		switch s.Code() {
+		case codes.Unauthenticated, codes.PermissionDenied:
+			return errorClassAuth
+		case codes.DeadlineExceeded:
+			return errorClassTimeout
+		case codes.InvalidArgument, codes.NotFound, codes.Unimplemented, codes.FailedPrecondition:
+			return errorClassRejected
+		case codes.Unavailable:
+			if class := classifyTransportMessage(s.Message()); class != "" {
+				return class
+			}
+		}
+	}
+
+	if class := classifyTransportMessage(err.Error()); class != "" {
+		return class
+	}
+	return fallback
+}
+
+func classifyTransportMessage(msg string) string {
+	switch {
+	case strings.Contains(msg, "no such host"),
+		strings.Contains(msg, "produced zero addresses"),
+		strings.Contains(msg, "name resolution"):
+		return errorClassDNS
+	case strings.Contains(msg, "connection refused"):
+		return errorClassRefused
+	case strings.Contains(msg, "handshake"), strings.Contains(msg, "tls:"), strings.Contains(msg, "x509:"):
+		return errorClassTLS
+	case strings.Contains(msg, "i/o timeout"):
+		return errorClassTimeout
+	}
+	return ""
+}
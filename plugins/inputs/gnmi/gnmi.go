@@ -8,9 +8,13 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/google/gnxi/utils/xpath"
 	"github.com/openconfig/gnmi/proto/gnmi"
@@ -64,14 +68,26 @@ type GNMI struct {
 	KeepaliveTime        config.Duration   `toml:"keepalive_time"`
 	KeepaliveTimeout     config.Duration   `toml:"keepalive_timeout"`
 	YangModelPaths       []string          `toml:"yang_model_paths"`
+	StaleThreshold       config.Duration   `toml:"stale_threshold"`
+	MaxKeyTags           int               `toml:"max_key_tags_per_metric"`
+	KeyPromotion         []keyPromotion    `toml:"key_promotion"`
+	DebugAddr            string            `toml:"debug_addr"`
 	Log                  telegraf.Logger   `toml:"-"`
 	common_tls.ClientConfig
 
 	// Internal state
-	internalAliases map[*pathInfo]string
-	decoder         *yangmodel.Decoder
-	cancel          context.CancelFunc
-	wg              sync.WaitGroup
+	internalAliases   map[*pathInfo]string
+	subscriptionPaths map[*pathInfo]string
+	decoder           *yangmodel.Decoder
+	keyPromoter       *keyPromoter
+	staleThresholds   map[string]time.Duration
+	minStaleThreshold time.Duration
+	started           time.Time
+	targets           []*targetState
+	debugListener     net.Listener
+	debugServer       *http.Server
+	cancel            context.CancelFunc
+	wg                sync.WaitGroup
 }
 
 type subscription struct {
@@ -82,6 +98,7 @@ type subscription struct {
 	SampleInterval    config.Duration `toml:"sample_interval"`
 	SuppressRedundant bool            `toml:"suppress_redundant"`
 	HeartbeatInterval config.Duration `toml:"heartbeat_interval"`
+	StaleThreshold    config.Duration `toml:"stale_threshold"`
 	TagOnly           bool            `toml:"tag_only" deprecated:"1.25.0;1.35.0;please use 'tag_subscription's instead"`
 
 	fullPath *gnmi.Path
@@ -102,6 +119,21 @@ func (c *GNMI) Init() error {
 	if time.Duration(c.Redial) <= 0 {
 		return errors.New("redial duration must be positive")
 	}
+	if c.StaleThreshold < 0 {
+		return errors.New("stale threshold must not be negative")
+	}
+
+	// Check the device addresses to avoid confusing errors when dialing
+	for _, addr := range c.Addresses {
+		if err := checkAddress(addr); err != nil {
+			return fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+	}
+	if c.DebugAddr != "" {
+		if err := checkDebugAddress(c.DebugAddr); err != nil {
+			return fmt.Errorf("invalid debug address %q: %w", c.DebugAddr, err)
+		}
+	}
 
 	// Check vendor_specific options configured by user
 	if err := choice.CheckSlice(c.VendorSpecific, supportedExtensions); err != nil {
@@ -181,6 +213,38 @@ func (c *GNMI) Init() error {
 		}
 	}
 
+	// Determine the staleness thresholds per subscription name falling back
+	// to the plugin-wide setting
+	c.staleThresholds = make(map[string]time.Duration, len(c.Subscriptions))
+	for _, s := range c.Subscriptions {
+		if s.StaleThreshold < 0 {
+			return fmt.Errorf("stale threshold of subscription %q must not be negative", s.Name)
+		}
+		threshold := time.Duration(s.StaleThreshold)
+		if threshold == 0 {
+			threshold = time.Duration(c.StaleThreshold)
+		}
+		if threshold > c.staleThresholds[s.Name] {
+			c.staleThresholds[s.Name] = threshold
+		}
+	}
+	for _, threshold := range c.staleThresholds {
+		if c.minStaleThreshold == 0 || threshold < c.minStaleThreshold {
+			c.minStaleThreshold = threshold
+		}
+	}
+
+	// Remember the subscription paths to account received data for staleness
+	// to the subscription independent of the measurement alias
+	c.subscriptionPaths = make(map[*pathInfo]string, len(c.Subscriptions))
+	for _, s := range c.Subscriptions {
+		path, err := parsePath(s.Origin, s.Path, "")
+		if err != nil {
+			return err
+		}
+		c.subscriptionPaths[newInfoFromPathWithoutKeys(path)] = s.Name
+	}
+
 	// Invert explicit alias list and prefill subscription names
 	c.internalAliases = make(map[*pathInfo]string, len(c.Subscriptions)+len(c.Aliases)+len(c.TagSubscriptions))
 	for _, s := range c.Subscriptions {
@@ -220,6 +284,16 @@ func (c *GNMI) Init() error {
 		return err
 	}
 
+	// Prepare the restrictions for promoting path keys to tags
+	if c.MaxKeyTags < 0 {
+		return errors.New("max_key_tags_per_metric must not be negative")
+	}
+	promoter, err := newKeyPromoter(c.KeyPromotion, c.MaxKeyTags)
+	if err != nil {
+		return err
+	}
+	c.keyPromoter = promoter
+
 	// Load the YANG models if specified by the user
 	if len(c.YangModelPaths) > 0 {
 		decoder, err := yangmodel.NewDecoder(c.YangModelPaths...)
@@ -267,21 +341,45 @@ func (c *GNMI) Start(acc telegraf.Accumulator) error {
 		ctx = metadata.AppendToOutgoingContext(ctx, "username", username, "password", password)
 	}
 
-	// Create a goroutine for each device, dial and subscribe
-	c.wg.Add(len(c.Addresses))
+	// Keep track of the state of each device e.g. for reporting the
+	// staleness of the data
+	c.started = time.Now()
+	c.targets = make([]*targetState, 0, len(c.Addresses))
+	subscriptions := make([]string, 0, len(c.Subscriptions))
+	for _, s := range c.Subscriptions {
+		if !slices.Contains(subscriptions, s.Name) {
+			subscriptions = append(subscriptions, s.Name)
+		}
+	}
+	sort.Strings(subscriptions)
 	for _, addr := range c.Addresses {
-		go func(addr string) {
+		target, err := newTargetState(addr, subscriptions)
+		if err != nil {
+			c.cancel()
+			return err
+		}
+		c.targets = append(c.targets, target)
+	}
+
+	// Serve the state of the devices for debugging if requested
+	if c.DebugAddr != "" {
+		if err := c.startDebugServer(); err != nil {
+			c.cancel()
+			return err
+		}
+	}
+
+	// Create a goroutine for each device, dial and subscribe
+	c.wg.Add(len(c.targets))
+	for _, target := range c.targets {
+		go func(target *targetState) {
 			defer c.wg.Done()
 
-			host, port, err := net.SplitHostPort(addr)
-			if err != nil {
-				acc.AddError(fmt.Errorf("unable to parse address %s: %w", addr, err))
-				return
-			}
 			h := handler{
-				host:                host,
-				port:                port,
+				host:                target.host,
+				port:                target.port,
 				aliases:             c.internalAliases,
+				subscriptionPaths:   c.subscriptionPaths,
 				tagsubs:             c.TagSubscriptions,
 				maxMsgSize:          int(c.MaxMsgSize),
 				vendorExt:           c.VendorSpecific,
@@ -292,6 +390,8 @@ func (c *GNMI) Start(acc telegraf.Accumulator) error {
 				tagPathPrefix:       c.PrefixTagKeyWithPath,
 				guessPathStrategy:   c.GuessPathStrategy,
 				decoder:             c.decoder,
+				keyPromoter:         c.keyPromoter,
+				target:              target,
 				log:                 c.Log,
 				ClientParameters: keepalive.ClientParameters{
 					Time:                time.Duration(c.KeepaliveTime),
@@ -309,20 +409,92 @@ func (c *GNMI) Start(acc telegraf.Accumulator) error {
 				case <-time.After(time.Duration(c.Redial)):
 				}
 			}
-		}(addr)
+		}(target)
 	}
 	return nil
 }
 
-func (*GNMI) Gather(telegraf.Accumulator) error {
+func (c *GNMI) Gather(acc telegraf.Accumulator) error {
+	if len(c.staleThresholds) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for _, target := range c.targets {
+		// Report the device as a whole using the smallest threshold
+		tags := map[string]string{
+			"source": target.host,
+			"port":   target.port,
+		}
+		addStaleness(acc, tags, target.lastUpdate.Load(), c.started, now, c.minStaleThreshold)
+
+		// Report the individual subscriptions
+		for name, lastUpdate := range target.subscriptions {
+			threshold, found := c.staleThresholds[name]
+			if !found {
+				continue
+			}
+			tags := map[string]string{
+				"source":       target.host,
+				"port":         target.port,
+				"subscription": name,
+			}
+			addStaleness(acc, tags, lastUpdate.Load(), c.started, now, threshold)
+		}
+	}
+
 	return nil
 }
 
+func addStaleness(acc telegraf.Accumulator, tags map[string]string, ts int64, started, now time.Time, threshold time.Duration) {
+	last := started
+	if ts > 0 {
+		last = time.Unix(0, ts)
+	} else {
+		tags["never_received"] = "true"
+	}
+	since := now.Sub(last)
+	fields := map[string]interface{}{
+		"seconds_since_last_update": since.Seconds(),
+		"stale":                     since > threshold,
+	}
+	acc.AddFields("gnmi_staleness", fields, tags, now)
+}
+
 func (c *GNMI) Stop() {
 	c.cancel()
+	if c.debugServer != nil {
+		// Close the listener and all open connections to not serve any data
+		// of a stopped plugin
+		c.debugServer.Close() //nolint:errcheck // Ignore the returned error as we cannot do anything about it anyway
+	}
 	c.wg.Wait()
 }
 
+func checkAddress(addr string) error {
+	if addr == "" {
+		return errors.New("empty address")
+	}
+	if strings.ContainsFunc(addr, unicode.IsSpace) {
+		return errors.New("address contains whitespace")
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return errors.New("missing host")
+	}
+	p, err := net.LookupPort("tcp", port)
+	if err != nil {
+		return err
+	}
+	if p == 0 {
+		return errors.New("port must not be zero")
+	}
+	return nil
+}
+
 func (s *subscription) buildSubscription() (*gnmi.Subscription, error) {
 	gnmiPath, err := parsePath(s.Origin, s.Path, "")
 	if err != nil {
@@ -4,7 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
@@ -15,7 +18,9 @@ import (
 	"github.com/openconfig/gnmi/proto/gnmi_ext"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 
@@ -24,6 +29,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/gnmi/extensions/jnpr_gnmi_extention"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/selfstat"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -1099,6 +1105,676 @@ func TestRedial(t *testing.T) {
 	wg.Wait()
 }
 
+func TestInvalidAddress(t *testing.T) {
+	tests := []struct {
+		name     string
+		address  string
+		expected string
+	}{
+		{
+			name:     "empty",
+			address:  "",
+			expected: `invalid address "": empty address`,
+		},
+		{
+			name:     "whitespace",
+			address:  "10.0.0.1 :57400",
+			expected: `invalid address "10.0.0.1 :57400": address contains whitespace`,
+		},
+		{
+			name:     "missing port",
+			address:  "10.0.0.1",
+			expected: `invalid address "10.0.0.1": address 10.0.0.1: missing port in address`,
+		},
+		{
+			name:     "missing host",
+			address:  ":57400",
+			expected: `invalid address ":57400": missing host`,
+		},
+		{
+			name:     "port out of range",
+			address:  "[2001:db8::1]:65536",
+			expected: `invalid address "[2001:db8::1]:65536": address 65536: invalid port`,
+		},
+		{
+			name:     "zero port",
+			address:  "router.example.com:0",
+			expected: `invalid address "router.example.com:0": port must not be zero`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &GNMI{
+				Log:       testutil.Logger{},
+				Addresses: []string{"127.0.0.1:57400", tt.address},
+				Encoding:  "proto",
+				Redial:    config.Duration(10 * time.Second),
+			}
+			require.EqualError(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestInvalidAddressOnStart(t *testing.T) {
+	// Addresses modified after Init must not be dialed
+	plugin := &GNMI{
+		Log:       testutil.Logger{},
+		Addresses: []string{"127.0.0.1:57400"},
+		Encoding:  "proto",
+		Redial:    config.Duration(10 * time.Second),
+	}
+	require.NoError(t, plugin.Init())
+	plugin.Addresses = append(plugin.Addresses, "10.0.0.1")
+
+	var acc testutil.Accumulator
+	require.EqualError(t, plugin.Start(&acc), `invalid address "10.0.0.1": address 10.0.0.1: missing port in address`)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		fallback string
+		expected string
+	}{
+		{
+			name:     "dns",
+			err:      fmt.Errorf("dialing failed: %w", &net.DNSError{Err: "no such host", Name: "foo"}),
+			fallback: errorClassUnknown,
+			expected: errorClassDNS,
+		},
+		{
+			name:     "dns in status",
+			err:      status.Error(codes.Unavailable, `name resolver error: produced zero addresses`),
+			fallback: errorClassStreamReset,
+			expected: errorClassDNS,
+		},
+		{
+			name:     "refused",
+			err:      status.Error(codes.Unavailable, `connection error: desc = "transport: Error while dialing: dial tcp 127.0.0.1:1: connect: connection refused"`),
+			fallback: errorClassStreamReset,
+			expected: errorClassRefused,
+		},
+		{
+			name:     "tls",
+			err:      status.Error(codes.Unavailable, `connection error: desc = "transport: authentication handshake failed: remote error: tls: handshake failure"`),
+			fallback: errorClassStreamReset,
+			expected: errorClassTLS,
+		},
+		{
+			name:     "auth",
+			err:      status.Error(codes.Unauthenticated, "invalid credentials"),
+			fallback: errorClassStreamReset,
+			expected: errorClassAuth,
+		},
+		{
+			name:     "rejected",
+			err:      status.Error(codes.InvalidArgument, "invalid path"),
+			fallback: errorClassStreamReset,
+			expected: errorClassRejected,
+		},
+		{
+			name:     "timeout",
+			err:      status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+			fallback: errorClassStreamReset,
+			expected: errorClassTimeout,
+		},
+		{
+			name:     "io timeout",
+			err:      errors.New("read tcp 127.0.0.1:1234->127.0.0.1:57400: i/o timeout"),
+			fallback: errorClassStreamReset,
+			expected: errorClassTimeout,
+		},
+		{
+			name:     "fallback",
+			err:      status.Error(codes.Unknown, "testerror"),
+			fallback: errorClassStreamReset,
+			expected: errorClassStreamReset,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, classifyError(tt.err, tt.fallback))
+		})
+	}
+}
+
+func TestCanceledErrorsNotCounted(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	// Use a unique source to not see errors counted by other tests
+	h := &handler{
+		host: "localhost",
+		port: port,
+		log:  testutil.Logger{},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	request := &gnmi.SubscribeRequest{}
+	require.ErrorContains(t, h.subscribeGNMI(ctx, &testutil.Accumulator{}, nil, request), "context canceled")
+
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_gnmi" {
+			continue
+		}
+		if source, _ := m.GetTag("source"); source != "localhost" {
+			continue
+		}
+		_, found := m.GetField("connection_errors")
+		require.False(t, found, "unexpected error counted: %v", m)
+	}
+}
+
+func TestDecodeErrorsCounted(t *testing.T) {
+	// Use a unique source to not see errors counted by other tests
+	h := &handler{
+		host:     "decode.example.com",
+		port:     "57400",
+		aliases:  map[*pathInfo]string{newInfoFromString("type:/model"): "model"},
+		tagStore: newTagStore(nil),
+		log:      testutil.Logger{},
+	}
+	notification := &gnmi.Notification{
+		Timestamp: 1543236572000000000,
+		Prefix:    &gnmi.Path{Origin: "type", Elem: []*gnmi.PathElem{{Name: "model"}}},
+		Update: []*gnmi.Update{
+			{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "value"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: []byte("{invalid")}},
+			},
+		},
+	}
+	h.handleSubscribeResponseUpdate(&testutil.Accumulator{}, &gnmi.SubscribeResponse_Update{Update: notification}, nil)
+
+	// Invalid payloads must not count as connection errors
+	var found bool
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_gnmi" {
+			continue
+		}
+		if source, _ := m.GetTag("source"); source != "decode.example.com" {
+			continue
+		}
+		_, connErr := m.GetField("connection_errors")
+		require.False(t, connErr, "unexpected connection error counted: %v", m)
+		if v, ok := m.GetField("decode_errors"); ok {
+			require.Equal(t, int64(1), v)
+			found = true
+		}
+	}
+	require.True(t, found, "decode error not counted")
+}
+
+func TestStaleness(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	// Reserve an address without any server to simulate a device that
+	// never delivers any data
+	unreachable, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	unreachableAddr := unreachable.Addr().String()
+	require.NoError(t, unreachable.Close())
+	_, unreachablePort, err := net.SplitHostPort(unreachableAddr)
+	require.NoError(t, err)
+
+	plugin := &GNMI{
+		Log:            testutil.Logger{},
+		Addresses:      []string{listener.Addr().String(), unreachableAddr},
+		Encoding:       "proto",
+		Redial:         config.Duration(10 * time.Second),
+		StaleThreshold: config.Duration(time.Hour),
+		Subscriptions: []subscription{
+			{
+				Name:             "model",
+				Origin:           "type",
+				Path:             "/model",
+				SubscriptionMode: "sample",
+				SampleInterval:   config.Duration(10 * time.Second),
+			},
+			{
+				Name:             "events",
+				Origin:           "type",
+				Path:             "/events",
+				SubscriptionMode: "on_change",
+				StaleThreshold:   config.Duration(time.Nanosecond),
+			},
+		},
+	}
+
+	grpcServer := grpc.NewServer()
+	gnmiServer := &mockServer{
+		subscribeF: func(server gnmi.GNMI_SubscribeServer) error {
+			notification := mockGNMINotification()
+			if err := server.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: notification}}); err != nil {
+				return err
+			}
+			<-server.Context().Done()
+			return nil
+		},
+		grpcServer: grpcServer,
+	}
+	gnmi.RegisterGNMIServer(grpcServer, gnmiServer)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := grpcServer.Serve(listener); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(&acc))
+	require.Eventually(t, func() bool {
+		return plugin.targets[0].lastUpdate.Load() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Zero(t, plugin.targets[1].lastUpdate.Load())
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Gather(&acc))
+	plugin.Stop()
+	grpcServer.Stop()
+	wg.Wait()
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"gnmi_staleness",
+			map[string]string{
+				"source":       "127.0.0.1",
+				"port":         port,
+				"subscription": "model",
+			},
+			map[string]interface{}{
+				"seconds_since_last_update": float64(0),
+				"stale":                     false,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"gnmi_staleness",
+			map[string]string{
+				"source":         "127.0.0.1",
+				"port":           port,
+				"subscription":   "events",
+				"never_received": "true",
+			},
+			map[string]interface{}{
+				"seconds_since_last_update": float64(0),
+				"stale":                     true,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"gnmi_staleness",
+			map[string]string{
+				"source":         "127.0.0.1",
+				"port":           unreachablePort,
+				"subscription":   "model",
+				"never_received": "true",
+			},
+			map[string]interface{}{
+				"seconds_since_last_update": float64(0),
+				"stale":                     false,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"gnmi_staleness",
+			map[string]string{
+				"source":         "127.0.0.1",
+				"port":           unreachablePort,
+				"subscription":   "events",
+				"never_received": "true",
+			},
+			map[string]interface{}{
+				"seconds_since_last_update": float64(0),
+				"stale":                     true,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"gnmi_staleness",
+			map[string]string{
+				"source": "127.0.0.1",
+				"port":   port,
+			},
+			map[string]interface{}{
+				"seconds_since_last_update": float64(0),
+				"stale":                     true,
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"gnmi_staleness",
+			map[string]string{
+				"source":         "127.0.0.1",
+				"port":           unreachablePort,
+				"never_received": "true",
+			},
+			map[string]interface{}{
+				"seconds_since_last_update": float64(0),
+				"stale":                     true,
+			},
+			time.Unix(0, 0),
+		),
+	}
+	actual := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected, actual,
+		testutil.IgnoreTime(), testutil.IgnoreFields("seconds_since_last_update"), testutil.SortMetrics())
+}
+
+func TestStaleThresholdInvalid(t *testing.T) {
+	plugin := &GNMI{
+		Log:            testutil.Logger{},
+		Encoding:       "proto",
+		Redial:         config.Duration(10 * time.Second),
+		StaleThreshold: config.Duration(-time.Second),
+	}
+	require.EqualError(t, plugin.Init(), "stale threshold must not be negative")
+
+	plugin = &GNMI{
+		Log:      testutil.Logger{},
+		Encoding: "proto",
+		Redial:   config.Duration(10 * time.Second),
+		Subscriptions: []subscription{
+			{
+				Name:           "model",
+				Path:           "/model",
+				StaleThreshold: config.Duration(-time.Second),
+			},
+		},
+	}
+	require.EqualError(t, plugin.Init(), `stale threshold of subscription "model" must not be negative`)
+}
+
+func TestStalenessDecodeFailure(t *testing.T) {
+	target, err := newTargetState("127.0.0.1:57400", []string{"model"})
+	require.NoError(t, err)
+	h := &handler{
+		host:     target.host,
+		port:     target.port,
+		aliases:  map[*pathInfo]string{newInfoFromString("type:/model"): "model"},
+		tagStore: newTagStore(nil),
+		target:   target,
+		log:      testutil.Logger{},
+
+		subscriptionPaths: map[*pathInfo]string{newInfoFromString("type:/model"): "model"},
+	}
+
+	// An update that cannot be decoded must not count as received data
+	notification := &gnmi.Notification{
+		Timestamp: 1543236572000000000,
+		Prefix:    &gnmi.Path{Origin: "type", Elem: []*gnmi.PathElem{{Name: "model"}}},
+		Update: []*gnmi.Update{
+			{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "value"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_JsonVal{JsonVal: []byte("{invalid")}},
+			},
+		},
+	}
+	var acc testutil.Accumulator
+	h.handleSubscribeResponseUpdate(&acc, &gnmi.SubscribeResponse_Update{Update: notification}, nil)
+	require.Zero(t, target.lastUpdate.Load())
+	require.Zero(t, target.subscriptions["model"].Load())
+
+	// A valid update does
+	notification.Update[0].Val = &gnmi.TypedValue{Value: &gnmi.TypedValue_IntVal{IntVal: 42}}
+	h.handleSubscribeResponseUpdate(&acc, &gnmi.SubscribeResponse_Update{Update: notification}, nil)
+	require.NotZero(t, target.lastUpdate.Load())
+	require.NotZero(t, target.subscriptions["model"].Load())
+}
+
+func TestStalenessWithAlias(t *testing.T) {
+	plugin := &GNMI{
+		Log:      testutil.Logger{},
+		Encoding: "proto",
+		Redial:   config.Duration(10 * time.Second),
+		Aliases: map[string]string{
+			"ifcounters": "openconfig:/interfaces/interface/state/counters",
+		},
+		Subscriptions: []subscription{
+			{
+				Name:           "interfaces",
+				Origin:         "openconfig",
+				Path:           "/interfaces",
+				StaleThreshold: config.Duration(time.Hour),
+			},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	target, err := newTargetState("127.0.0.1:57400", []string{"interfaces"})
+	require.NoError(t, err)
+	h := &handler{
+		host:              target.host,
+		port:              target.port,
+		aliases:           plugin.internalAliases,
+		subscriptionPaths: plugin.subscriptionPaths,
+		tagStore:          newTagStore(nil),
+		target:            target,
+		log:               testutil.Logger{},
+	}
+
+	// Data is accounted to the subscription even if reported with an alias
+	notification := &gnmi.Notification{
+		Timestamp: 1543236572000000000,
+		Prefix: &gnmi.Path{
+			Origin: "openconfig",
+			Elem: []*gnmi.PathElem{
+				{Name: "interfaces"},
+				{Name: "interface", Key: map[string]string{"name": "eth0"}},
+				{Name: "state"},
+				{Name: "counters"},
+			},
+		},
+		Update: []*gnmi.Update{
+			{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "in-octets"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 42}},
+			},
+		},
+	}
+	var acc testutil.Accumulator
+	h.handleSubscribeResponseUpdate(&acc, &gnmi.SubscribeResponse_Update{Update: notification}, nil)
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	require.Equal(t, "ifcounters", acc.GetTelegrafMetrics()[0].Name())
+	require.NotZero(t, target.lastUpdate.Load())
+	require.NotZero(t, target.subscriptions["interfaces"].Load())
+}
+
+func TestDebugAddressInvalid(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0:8080", "192.168.1.1:8080", ":8080", "localhost"} {
+		plugin := &GNMI{
+			Log:       testutil.Logger{},
+			Encoding:  "proto",
+			Redial:    config.Duration(10 * time.Second),
+			DebugAddr: addr,
+		}
+		require.ErrorContains(t, plugin.Init(), "invalid debug address", addr)
+	}
+}
+
+func TestTargetRecentErrors(t *testing.T) {
+	target, err := newTargetState("127.0.0.1:57400", nil)
+	require.NoError(t, err)
+	require.Empty(t, target.recentErrors())
+
+	for i := range maxTargetErrors + 3 {
+		target.failed(errorClassUnknown, fmt.Errorf("error %d", i))
+	}
+	errs := target.recentErrors()
+	require.Len(t, errs, maxTargetErrors)
+	require.Equal(t, fmt.Sprintf("error %d", maxTargetErrors+2), errs[0].message)
+	require.Equal(t, "error 3", errs[maxTargetErrors-1].message)
+}
+
+func TestDebugEndpoint(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	// Reserve an address without any server to simulate a failing device
+	unreachable, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	unreachableAddr := unreachable.Addr().String()
+	require.NoError(t, unreachable.Close())
+
+	plugin := &GNMI{
+		Log:       testutil.Logger{},
+		Addresses: []string{listener.Addr().String(), unreachableAddr},
+		Encoding:  "proto",
+		Redial:    config.Duration(10 * time.Second),
+		DebugAddr: "127.0.0.1:0",
+		Subscriptions: []subscription{
+			{
+				Name:             "model",
+				Origin:           "type",
+				Path:             "/model",
+				SubscriptionMode: "sample",
+				SampleInterval:   config.Duration(10 * time.Second),
+			},
+			{
+				Name:             "events",
+				Origin:           "type",
+				Path:             "/events",
+				SubscriptionMode: "on_change",
+				StaleThreshold:   config.Duration(time.Nanosecond),
+			},
+		},
+	}
+
+	grpcServer := grpc.NewServer()
+	gnmiServer := &mockServer{
+		subscribeF: func(server gnmi.GNMI_SubscribeServer) error {
+			notification := mockGNMINotification()
+			if err := server.Send(&gnmi.SubscribeResponse{Response: &gnmi.SubscribeResponse_Update{Update: notification}}); err != nil {
+				return err
+			}
+			<-server.Context().Done()
+			return nil
+		},
+		grpcServer: grpcServer,
+	}
+	gnmi.RegisterGNMIServer(grpcServer, gnmiServer)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := grpcServer.Serve(listener); err != nil {
+			t.Error(err)
+		}
+	}()
+	defer wg.Wait()
+	defer grpcServer.Stop()
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+	require.Eventually(t, func() bool {
+		return plugin.targets[0].lastUpdate.Load() > 0 && len(plugin.targets[1].recentErrors()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	base := "http://" + plugin.debugListener.Addr().String() + "/targets"
+	get := func(url string, v interface{}) int {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+		}
+		return resp.StatusCode
+	}
+
+	// Check the index of all devices
+	var index []debugTargetSummary
+	require.Equal(t, http.StatusOK, get(base, &index))
+	require.Len(t, index, 2)
+	require.Equal(t, listener.Addr().String(), index[0].Address)
+	require.Equal(t, "127.0.0.1", index[0].Source)
+	require.Equal(t, port, index[0].Port)
+	require.True(t, index[0].Connected)
+	require.NotNil(t, index[0].LastUpdate)
+	require.Equal(t, []string{"events"}, index[0].Stale)
+	require.Equal(t, unreachableAddr, index[1].Address)
+	require.False(t, index[1].Connected)
+	require.Nil(t, index[1].LastUpdate)
+	require.Equal(t, []string{"events"}, index[1].Stale)
+
+	// Check the details of the devices
+	var details debugTarget
+	require.Equal(t, http.StatusOK, get(base+"/"+listener.Addr().String(), &details))
+	require.True(t, details.Connected)
+	require.Empty(t, details.Errors)
+	require.Len(t, details.Subscriptions, 2)
+	require.Equal(t, "model", details.Subscriptions[0].Name)
+	require.Equal(t, "/model", details.Subscriptions[0].Path)
+	require.Equal(t, "sample", details.Subscriptions[0].Mode)
+	require.Equal(t, "10s", details.Subscriptions[0].SampleInterval)
+	require.NotNil(t, details.Subscriptions[0].LastUpdate)
+	require.False(t, details.Subscriptions[0].Stale)
+	require.Equal(t, "events", details.Subscriptions[1].Name)
+	require.Nil(t, details.Subscriptions[1].LastUpdate)
+	require.True(t, details.Subscriptions[1].Stale)
+
+	details = debugTarget{}
+	require.Equal(t, http.StatusOK, get(base+"/"+unreachableAddr, &details))
+	require.False(t, details.Connected)
+	require.NotEmpty(t, details.Errors)
+	require.Equal(t, errorClassRefused, details.Errors[0].Class)
+	require.NotEmpty(t, details.Errors[0].Message)
+
+	// Unknown devices are not found
+	require.Equal(t, http.StatusNotFound, get(base+"/127.0.0.1:1", nil))
+}
+
+func TestDebugEndpointStop(t *testing.T) {
+	plugin := &GNMI{
+		Log:       testutil.Logger{},
+		Addresses: []string{"127.0.0.1:57400"},
+		Encoding:  "proto",
+		Redial:    config.Duration(10 * time.Second),
+		DebugAddr: "127.0.0.1:0",
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+
+	// Use a client keeping the connection alive
+	client := &http.Client{Transport: &http.Transport{}}
+	defer client.CloseIdleConnections()
+	url := "http://" + plugin.debugListener.Addr().String() + "/targets"
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	_, err = io.Copy(io.Discard, resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Existing connections must not serve data after stopping the plugin
+	plugin.Stop()
+	resp, err = client.Get(url)
+	if err == nil {
+		require.NoError(t, resp.Body.Close())
+	}
+	require.Error(t, err)
+}
+
 func TestCases(t *testing.T) {
 	// Get all testcase directories
 	folders, err := os.ReadDir("testcases")
@@ -1226,3 +1902,302 @@ func TestCases(t *testing.T) {
 		})
 	}
 }
+
+func FuzzPathInfo(f *testing.F) {
+	// Seed the corpus with the paths contained in the test-cases
+	folders, err := os.ReadDir("testcases")
+	require.NoError(f, err)
+	for _, folder := range folders {
+		if !folder.IsDir() {
+			continue
+		}
+		for _, response := range loadFuzzResponses(f, folder.Name()) {
+			update := response.GetUpdate()
+			if update == nil {
+				continue
+			}
+			paths := []*gnmi.Path{update.GetPrefix()}
+			for _, u := range update.GetUpdate() {
+				paths = append(paths, u.GetPath())
+			}
+			for _, p := range paths {
+				if p == nil {
+					continue
+				}
+				buf, err := proto.Marshal(p)
+				require.NoError(f, err)
+				f.Add(buf, false)
+			}
+		}
+	}
+	f.Add([]byte{}, true)
+
+	f.Fuzz(func(_ *testing.T, input []byte, withNilElement bool) {
+		var path gnmi.Path
+		if err := proto.Unmarshal(input, &path); err != nil {
+			return
+		}
+		if withNilElement {
+			path.Elem = append(path.Elem, nil)
+		}
+
+		info := newInfoFromPath(&path, nil)
+		info = info.append(&path, nil)
+		info = info.appendSegments("foo", "", "bar")
+		info.tags(false)
+		info.tags(true)
+		info.relative(newInfoFromPathWithoutKeys(&path), true)
+		info.keepCommonPart(newInfoFromString(info.String()))
+		info.equalsPathNoKeys(&path)
+		_ = info.fullPath()
+		_ = info.dir()
+		_ = info.base()
+		for _, kv := range info.keyValues {
+			getElementsKeys(info, []string{kv.name})
+		}
+	})
+}
+
+func FuzzUpdateJSON(f *testing.F) {
+	// Seed the corpus with the JSON values contained in the test-cases
+	folders, err := os.ReadDir("testcases")
+	require.NoError(f, err)
+	for _, folder := range folders {
+		if !folder.IsDir() {
+			continue
+		}
+		for _, response := range loadFuzzResponses(f, folder.Name()) {
+			for _, u := range response.GetUpdate().GetUpdate() {
+				if v := u.GetVal().GetJsonIetfVal(); v != nil {
+					f.Add(v)
+				}
+				if v := u.GetVal().GetJsonVal(); v != nil {
+					f.Add(v)
+				}
+			}
+		}
+	}
+	f.Add([]byte(`{"a": [1, {"b": null}], "ns:c": {"ns:d": "e"}}`))
+
+	h := &handler{log: testutil.Logger{}}
+	path := newInfoFromString("/interfaces/interface/state")
+	f.Fuzz(func(_ *testing.T, input []byte) {
+		//nolint:errcheck // fuzz testing can give lots of errors, but we just want to test for crashes
+		processJSON(path, input)
+		//nolint:errcheck // fuzz testing can give lots of errors, but we just want to test for crashes
+		h.processJSONIETF(path, input)
+	})
+}
+
+func loadFuzzResponses(f *testing.F, testcase string) []*gnmi.SubscribeResponse {
+	buf, err := os.ReadFile(filepath.Join("testcases", testcase, "responses.json"))
+	require.NoError(f, err)
+	var entries []json.RawMessage
+	require.NoError(f, json.Unmarshal(buf, &entries))
+	responses := make([]*gnmi.SubscribeResponse, 0, len(entries))
+	for _, entry := range entries {
+		var response gnmi.SubscribeResponse
+		require.NoError(f, protojson.Unmarshal(entry, &response))
+		responses = append(responses, &response)
+	}
+	return responses
+}
+
+func TestKeyPromotionInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxTags  int
+		rules    []keyPromotion
+		expected string
+	}{
+		{
+			name:     "negative limit",
+			maxTags:  -1,
+			expected: "max_key_tags_per_metric must not be negative",
+		},
+		{
+			name:     "invalid denied action",
+			rules:    []keyPromotion{{Path: "/components", Denied: "append"}},
+			expected: `invalid 'denied' setting "append" for key promotion 1`,
+		},
+		{
+			name:     "invalid filter",
+			rules:    []keyPromotion{{Path: "/components"}, {Path: "/interfaces", Include: []string{"[a"}}},
+			expected: "creating filter for key promotion 2 failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &GNMI{
+				Log:          testutil.Logger{},
+				Encoding:     "proto",
+				Redial:       config.Duration(10 * time.Second),
+				MaxKeyTags:   tt.maxTags,
+				KeyPromotion: tt.rules,
+			}
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestKeyPromotion(t *testing.T) {
+	path := &gnmi.Path{
+		Origin: "openconfig",
+		Elem: []*gnmi.PathElem{
+			{Name: "components"},
+			{Name: "component", Key: map[string]string{"name": "CPU0"}},
+			{Name: "sensor", Key: map[string]string{"index": "4", "type": "temp"}},
+			{Name: "state"},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		maxTags         int
+		rules           []keyPromotion
+		expectedTags    map[string]string
+		expectedDemoted []string
+		expectedDropped []string
+	}{
+		{
+			name:         "no rule matching",
+			rules:        []keyPromotion{{Path: "/interfaces", Exclude: []string{"*"}}},
+			expectedTags: map[string]string{"name": "CPU0", "index": "4", "type": "temp"},
+		},
+		{
+			name:         "exclude drop",
+			rules:        []keyPromotion{{Path: "/components/component/sensor", Exclude: []string{"index"}}},
+			expectedTags: map[string]string{"name": "CPU0", "type": "temp"},
+		},
+		{
+			name: "exclude field",
+			rules: []keyPromotion{
+				{Path: "/components/component/sensor", Exclude: []string{"index"}, Denied: "field"},
+			},
+			expectedTags:    map[string]string{"name": "CPU0", "type": "temp"},
+			expectedDemoted: []string{"index=4"},
+		},
+		{
+			name:         "include allowlist",
+			rules:        []keyPromotion{{Path: "/components", Include: []string{"name", "ty*"}}},
+			expectedTags: map[string]string{"name": "CPU0", "type": "temp"},
+		},
+		{
+			name: "longest rule wins",
+			rules: []keyPromotion{
+				{Path: "/components", Exclude: []string{"*"}, Denied: "field"},
+				{Path: "openconfig:/components/component/sensor", Include: []string{"type"}},
+			},
+			expectedTags:    map[string]string{"type": "temp"},
+			expectedDemoted: []string{"name=CPU0"},
+		},
+		{
+			name:         "origin mismatch",
+			rules:        []keyPromotion{{Path: "other:/components", Exclude: []string{"*"}}},
+			expectedTags: map[string]string{"name": "CPU0", "index": "4", "type": "temp"},
+		},
+		{
+			name:            "limit",
+			maxTags:         2,
+			expectedTags:    map[string]string{"name": "CPU0", "index": "4"},
+			expectedDropped: []string{"type"},
+		},
+		{
+			name:            "limit after denied keys",
+			maxTags:         1,
+			rules:           []keyPromotion{{Path: "/components/component", Exclude: []string{"name"}, Denied: "field"}},
+			expectedTags:    map[string]string{"index": "4"},
+			expectedDemoted: []string{"name=CPU0"},
+			expectedDropped: []string{"type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promoter, err := newKeyPromoter(tt.rules, tt.maxTags)
+			require.NoError(t, err)
+			require.NotNil(t, promoter)
+
+			info := newInfoFromPath(path)
+			promoted, demoted, dropped := promoter.apply(info, info.keys(false))
+			tags := make(map[string]string, len(promoted))
+			for _, k := range promoted {
+				tags[k.tag] = k.value
+			}
+			require.Equal(t, tt.expectedTags, tags)
+			require.Equal(t, tt.expectedDemoted, demoted)
+			require.Equal(t, tt.expectedDropped, dropped)
+		})
+	}
+}
+
+func TestKeyPromotionLimitWarning(t *testing.T) {
+	promoter, err := newKeyPromoter(nil, 1)
+	require.NoError(t, err)
+
+	var logger testutil.CaptureLogger
+	h := &handler{
+		host:        "127.0.0.1",
+		port:        "57400",
+		aliases:     map[*pathInfo]string{newInfoFromString("openconfig:/components"): "sensors"},
+		tagStore:    newTagStore(nil),
+		keyPromoter: promoter,
+		log:         &logger,
+	}
+	notification := &gnmi.Notification{
+		Timestamp: 1543236572000000000,
+		Prefix: &gnmi.Path{
+			Origin: "openconfig",
+			Elem: []*gnmi.PathElem{
+				{Name: "components"},
+				{Name: "component", Key: map[string]string{"name": "CPU0"}},
+				{Name: "sensor", Key: map[string]string{"index": "4"}},
+			},
+		},
+		Update: []*gnmi.Update{
+			{
+				Path: &gnmi.Path{Elem: []*gnmi.PathElem{{Name: "temperature"}}},
+				Val:  &gnmi.TypedValue{Value: &gnmi.TypedValue_UintVal{UintVal: 40}},
+			},
+		},
+	}
+
+	// Truncating keys must be reported once per handler
+	var acc testutil.Accumulator
+	h.handleSubscribeResponseUpdate(&acc, &gnmi.SubscribeResponse_Update{Update: notification}, nil)
+	h.handleSubscribeResponseUpdate(&acc, &gnmi.SubscribeResponse_Update{Update: notification}, nil)
+
+	var warnings []string
+	for _, e := range logger.Messages() {
+		if e.Level == testutil.LevelWarn {
+			warnings = append(warnings, e.Text)
+		}
+	}
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], `"openconfig:/components/component/sensor/temperature"`)
+	require.Contains(t, warnings[0], "dropping key(s) index.")
+}
+
+func TestKeyPromotionRemovedSegments(t *testing.T) {
+	// Elements consisting of a namespace only are removed when normalizing
+	// the path, so the rules must still match the correct element
+	path := &gnmi.Path{
+		Elem: []*gnmi.PathElem{
+			{Name: "openconfig:"},
+			{Name: "components"},
+			{Name: "component", Key: map[string]string{"name": "CPU0"}},
+			{Name: "sensor", Key: map[string]string{"index": "4"}},
+		},
+	}
+	info := newInfoFromPath(path)
+	require.Equal(t, "openconfig:/components/component/sensor", info.String())
+
+	promoter, err := newKeyPromoter([]keyPromotion{{Path: "/components/component/sensor", Exclude: []string{"*"}}}, 0)
+	require.NoError(t, err)
+	promoted, demoted, dropped := promoter.apply(info, info.keys(false))
+	require.Len(t, promoted, 1)
+	require.Equal(t, "name", promoted[0].tag)
+	require.Empty(t, demoted)
+	require.Empty(t, dropped)
+}
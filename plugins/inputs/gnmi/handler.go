@@ -32,13 +32,21 @@ import (
 
 const eidJuniperTelemetryHeader = 1
 
+// Define the warning to show if path keys are dropped due to the tag limit
+const keyLimitWarning = `Reached the limit of %d tags from path keys for path %q, dropping key(s) %s.
+Metrics only differing in those keys are merged into one series, overwriting
+field values and losing data. Consider using 'key_promotion' with
+denied = "field" to keep those keys. This message is only printed once.`
+
 type handler struct {
 	host                string
 	port                string
 	aliases             map[*pathInfo]string
+	subscriptionPaths   map[*pathInfo]string
 	tagsubs             []tagSubscription
 	maxMsgSize          int
 	emptyNameWarnShown  bool
+	keyLimitWarnShown   bool
 	vendorExt           []string
 	tagStore            *tagStore
 	trace               bool
@@ -47,6 +55,8 @@ type handler struct {
 	tagPathPrefix       bool
 	guessPathStrategy   string
 	decoder             *yangmodel.Decoder
+	keyPromoter         *keyPromoter
+	target              *targetState
 	log                 telegraf.Logger
 	keepalive.ClientParameters
 }
@@ -82,21 +92,32 @@ func (h *handler) subscribeGNMI(ctx context.Context, acc telegraf.Accumulator, t
 	address := net.JoinHostPort(h.host, h.port)
 	client, err := grpc.NewClient(address, opts...)
 	if err != nil {
+		h.countError(err, errorClassUnknown)
 		return fmt.Errorf("failed to dial: %w", err)
 	}
 	defer client.Close()
 
 	subscribeClient, err := gnmi.NewGNMIClient(client).Subscribe(ctx)
 	if err != nil {
+		if ctx.Err() == nil {
+			h.countError(err, errorClassRejected)
+		}
 		return fmt.Errorf("failed to setup subscription: %w", err)
 	}
 
 	// If io.EOF is returned, the stream may have ended and stream status
 	// can be determined by calling Recv.
 	if err := subscribeClient.Send(request); err != nil && !errors.Is(err, io.EOF) {
+		if ctx.Err() == nil {
+			h.countError(err, errorClassRejected)
+		}
 		return fmt.Errorf("failed to send subscription request: %w", err)
 	}
 	connectStat.Set(1)
+	if h.target != nil {
+		h.target.connected.Store(true)
+		defer h.target.connected.Store(false)
+	}
 	h.log.Debugf("Connection to gNMI device %s established", address)
 
 	defer h.log.Debugf("Connection to gNMI device %s closed", address)
@@ -104,6 +125,7 @@ func (h *handler) subscribeGNMI(ctx context.Context, acc telegraf.Accumulator, t
 		var reply *gnmi.SubscribeResponse
 		if reply, err = subscribeClient.Recv(); err != nil {
 			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				h.countError(err, errorClassStreamReset)
 				return fmt.Errorf("aborted gNMI subscription: %w", err)
 			}
 			break
@@ -125,10 +147,34 @@ func (h *handler) subscribeGNMI(ctx context.Context, acc telegraf.Accumulator, t
 	return nil
 }
 
+// Count the error in the internal statistics according to its class
+func (h *handler) countError(err error, fallback string) {
+	class := classifyError(err, fallback)
+	tags := map[string]string{
+		"source":      h.host,
+		"error_class": class,
+	}
+	selfstat.Register("gnmi", "connection_errors", tags).Incr(1)
+	if h.target != nil {
+		h.target.failed(class, err)
+	}
+}
+
+// Count errors in the received data separately from the connection errors
+// as those are caused by the payload and not the connection
+func (h *handler) countDecodeError(err error) {
+	selfstat.Register("gnmi", "decode_errors", map[string]string{"source": h.host}).Incr(1)
+	if h.target != nil {
+		h.target.failed(errorClassDecode, err)
+	}
+}
+
 // Handle SubscribeResponse_Update message from gNMI and parse contained telemetry data
 func (h *handler) handleSubscribeResponseUpdate(acc telegraf.Accumulator, response *gnmi.SubscribeResponse_Update, extension []*gnmi_ext.Extension) {
 	grouper := metric.NewSeriesGrouper()
 	timestamp := time.Unix(0, response.Update.Timestamp)
+	received := time.Now()
+	var decoded bool
 
 	// Extract tags from potential extension in the update notification
 	headerTags := make(map[string]string)
@@ -173,13 +219,16 @@ func (h *handler) handleSubscribeResponseUpdate(acc telegraf.Accumulator, respon
 	var valueFields []updateField
 	for _, update := range response.Update.Update {
 		fullPath := prefix.append(update.Path)
-		if update.Path.Origin != "" {
-			fullPath.origin = update.Path.Origin
+		if origin := update.GetPath().GetOrigin(); origin != "" {
+			fullPath.origin = origin
 		}
 
 		fields, err := h.newFieldsFromUpdate(fullPath, update)
 		if err != nil {
 			h.log.Errorf("Processing update %v failed: %v", update, err)
+			h.countDecodeError(err)
+		} else if len(fields) > 0 {
+			decoded = true
 		}
 
 		// Prepare tags from prefix
@@ -223,18 +272,39 @@ func (h *handler) handleSubscribeResponseUpdate(acc telegraf.Accumulator, respon
 			continue
 		}
 
-		// Prepare tags from prefix
-		fieldTags := field.path.tags(h.tagPathPrefix)
-		tags := make(map[string]string, len(headerTags)+len(fieldTags))
+		// Prepare tags from prefix restricted to the path keys to promote
+		keys := field.path.keys(h.tagPathPrefix)
+		promoted := keys
+		var demoted []string
+		if h.keyPromoter != nil {
+			var dropped []string
+			promoted, demoted, dropped = h.keyPromoter.apply(field.path, keys)
+			if len(dropped) > 0 && !h.keyLimitWarnShown {
+				h.log.Warnf(keyLimitWarning, h.keyPromoter.maxTags, field.path, strings.Join(dropped, ", "))
+				h.keyLimitWarnShown = true
+			}
+		}
+		tags := make(map[string]string, len(headerTags)+len(promoted))
 		for key, val := range headerTags {
 			tags[key] = val
 		}
-		for key, val := range fieldTags {
-			tags[key] = val
+		for _, k := range promoted {
+			tags[k.tag] = k.value
 		}
 
-		// Add the tags derived via tag-subscriptions
-		for k, v := range h.tagStore.lookup(field.path, tags) {
+		// Add the tags derived via tag-subscriptions. Matching is done on all
+		// path keys independent of whether they are promoted to tags.
+		lookupTags := tags
+		if len(promoted) != len(keys) {
+			lookupTags = make(map[string]string, len(tags)+len(keys))
+			for key, val := range tags {
+				lookupTags[key] = val
+			}
+			for _, k := range keys {
+				lookupTags[k.tag] = k.value
+			}
+		}
+		for k, v := range h.tagStore.lookup(field.path, lookupTags) {
 			tags[k] = v
 		}
 
@@ -284,7 +354,16 @@ func (h *handler) handleSubscribeResponseUpdate(acc telegraf.Accumulator, respon
 			h.log.Errorf("Invalid empty path %q with alias %q", field.path.String(), aliasPath)
 			continue
 		}
+		key = fieldKeyWithDemotedKeys(key, demoted)
 		grouper.Add(name, tags, timestamp, key, field.value)
+		if h.target != nil {
+			h.target.updated(h.lookupSubscription(field.path), received)
+		}
+	}
+
+	// Only account for updates where at least some data could be decoded
+	if decoded && h.target != nil {
+		h.target.lastUpdate.Store(received.UnixNano())
 	}
 
 	// Add grouped measurements
@@ -318,6 +397,22 @@ func (h *handler) lookupAlias(info *pathInfo) (aliasPath, alias string) {
 	return candidates[0].path, candidates[0].alias
 }
 
+// Find the subscription that delivered the data for the given path, i.e.
+// the one with the longest path matching
+func (h *handler) lookupSubscription(info *pathInfo) string {
+	var name string
+	length := -1
+	for p, n := range h.subscriptionPaths {
+		if !p.isSubPathOf(info) {
+			continue
+		}
+		if len(p.segments) > length || (len(p.segments) == length && n < name) {
+			name, length = n, len(p.segments)
+		}
+	}
+	return name
+}
+
 func guessPrefixFromUpdate(fields []updateField) string {
 	if len(fields) == 0 {
 		return ""
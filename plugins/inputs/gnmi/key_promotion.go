@@ -0,0 +1,97 @@
+package gnmi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/telegraf/filter"
+)
+
+type keyPromotion struct {
+	Path    string   `toml:"path"`
+	Include []string `toml:"include"`
+	Exclude []string `toml:"exclude"`
+	Denied  string   `toml:"denied"`
+
+	info   *pathInfo
+	filter filter.Filter
+}
+
+type keyPromoter struct {
+	rules   []keyPromotion
+	maxTags int
+}
+
+func newKeyPromoter(rules []keyPromotion, maxTags int) (*keyPromoter, error) {
+	if len(rules) == 0 && maxTags <= 0 {
+		return nil, nil
+	}
+
+	p := &keyPromoter{
+		rules:   make([]keyPromotion, 0, len(rules)),
+		maxTags: maxTags,
+	}
+	for i, r := range rules {
+		switch r.Denied {
+		case "":
+			r.Denied = "drop"
+		case "drop", "field":
+		default:
+			return nil, fmt.Errorf("invalid 'denied' setting %q for key promotion %d", r.Denied, i+1)
+		}
+
+		f, err := filter.NewIncludeExcludeFilter(r.Include, r.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("creating filter for key promotion %d failed: %w", i+1, err)
+		}
+		r.filter = f
+		r.info = newInfoFromString(r.Path)
+		p.rules = append(p.rules, r)
+	}
+
+	return p, nil
+}
+
+// Find the most specific rule for the path element of the given key segment
+func (p *keyPromoter) lookup(path *pathInfo, element keySegment) *keyPromotion {
+	var rule *keyPromotion
+	for i, r := range p.rules {
+		if element.depth > len(path.segments) || !r.info.isPrefixOf(path.origin, path.segments[:element.depth]) {
+			continue
+		}
+		if rule == nil || len(r.info.segments) > len(rule.info.segments) {
+			rule = &p.rules[i]
+		}
+	}
+	return rule
+}
+
+// Filter the path keys to promote to tags and return the denied keys that
+// should become part of the field name instead as well as the names of the
+// keys dropped due to the tag limit
+func (p *keyPromoter) apply(path *pathInfo, keys []pathKey) (promoted []pathKey, demoted, dropped []string) {
+	promoted = make([]pathKey, 0, len(keys))
+	for _, k := range keys {
+		if rule := p.lookup(path, k.segment); rule != nil && !rule.filter.Match(k.name) {
+			if rule.Denied == "field" {
+				demoted = append(demoted, k.name+"="+k.value)
+			}
+			continue
+		}
+
+		if p.maxTags > 0 && len(promoted) >= p.maxTags {
+			dropped = append(dropped, k.name)
+			continue
+		}
+		promoted = append(promoted, k)
+	}
+
+	return promoted, demoted, dropped
+}
+
+func fieldKeyWithDemotedKeys(key string, demoted []string) string {
+	if len(demoted) == 0 {
+		return key
+	}
+	return key + "[" + strings.Join(demoted, "][") + "]"
+}
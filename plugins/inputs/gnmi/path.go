@@ -1,15 +1,17 @@
 package gnmi
 
 import (
+	"sort"
 	"strings"
 
 	"github.com/openconfig/gnmi/proto/gnmi"
 )
 
 type keySegment struct {
-	name string
-	path string
-	kv   map[string]string
+	name  string
+	path  string
+	depth int
+	kv    map[string]string
 }
 
 type segment struct {
@@ -47,7 +49,7 @@ func newInfoFromPathWithoutKeys(path *gnmi.Path) *pathInfo {
 		segments: make([]segment, 0, len(path.Elem)),
 	}
 	for _, elem := range path.Elem {
-		if elem.Name == "" {
+		if elem.GetName() == "" {
 			continue
 		}
 		info.segments = append(info.segments, segment{id: elem.Name})
@@ -73,7 +75,7 @@ func newInfoFromPath(paths ...*gnmi.Path) *pathInfo {
 			continue
 		}
 		for _, elem := range p.Elem {
-			if elem.Name == "" {
+			if elem.GetName() == "" {
 				continue
 			}
 			info.segments = append(info.segments, segment{id: elem.Name})
@@ -82,9 +84,10 @@ func newInfoFromPath(paths ...*gnmi.Path) *pathInfo {
 				continue
 			}
 			keyInfo := keySegment{
-				name: elem.Name,
-				path: info.String(),
-				kv:   make(map[string]string, len(elem.Key)),
+				name:  elem.Name,
+				path:  info.String(),
+				depth: len(info.segments),
+				kv:    make(map[string]string, len(elem.Key)),
 			}
 			for k, v := range elem.Key {
 				keyInfo.kv[k] = v
@@ -112,9 +115,10 @@ func (pi *pathInfo) append(paths ...*gnmi.Path) *pathInfo {
 	}
 	for _, elem := range pi.keyValues {
 		keyInfo := keySegment{
-			name: elem.name,
-			path: elem.path,
-			kv:   make(map[string]string, len(elem.kv)),
+			name:  elem.name,
+			path:  elem.path,
+			depth: elem.depth,
+			kv:    make(map[string]string, len(elem.kv)),
 		}
 		for k, v := range elem.kv {
 			keyInfo.kv[k] = v
@@ -124,8 +128,8 @@ func (pi *pathInfo) append(paths ...*gnmi.Path) *pathInfo {
 
 	// Add the new segments
 	for _, p := range paths {
-		for _, elem := range p.Elem {
-			if elem.Name == "" {
+		for _, elem := range p.GetElem() {
+			if elem.GetName() == "" {
 				continue
 			}
 			path.segments = append(path.segments, segment{id: elem.Name})
@@ -134,9 +138,10 @@ func (pi *pathInfo) append(paths ...*gnmi.Path) *pathInfo {
 				continue
 			}
 			keyInfo := keySegment{
-				name: elem.Name,
-				path: path.String(),
-				kv:   make(map[string]string, len(elem.Key)),
+				name:  elem.Name,
+				path:  path.String(),
+				depth: len(path.segments),
+				kv:    make(map[string]string, len(elem.Key)),
 			}
 			for k, v := range elem.Key {
 				keyInfo.kv[k] = v
@@ -160,9 +165,10 @@ func (pi *pathInfo) appendSegments(segments ...string) *pathInfo {
 	}
 	for _, elem := range pi.keyValues {
 		keyInfo := keySegment{
-			name: elem.name,
-			path: elem.path,
-			kv:   make(map[string]string, len(elem.kv)),
+			name:  elem.name,
+			path:  elem.path,
+			depth: elem.depth,
+			kv:    make(map[string]string, len(elem.kv)),
 		}
 		for k, v := range elem.kv {
 			keyInfo.kv[k] = v
@@ -208,6 +214,22 @@ func (pi *pathInfo) normalize() {
 			segments = append(segments, s)
 		}
 	}
+	if len(segments) == len(pi.segments) {
+		return
+	}
+
+	// Correct the depth of the key elements for the removed segments
+	depths := make([]int, len(pi.segments)+1)
+	var kept int
+	for i, s := range pi.segments {
+		if s.id != "" {
+			kept++
+		}
+		depths[i+1] = kept
+	}
+	for i := range pi.keyValues {
+		pi.keyValues[i].depth = depths[pi.keyValues[i].depth]
+	}
 	pi.segments = segments
 }
 
@@ -216,7 +238,7 @@ func (pi *pathInfo) equalsPathNoKeys(path *gnmi.Path) bool {
 		return false
 	}
 	for i, s := range pi.segments {
-		if s.id != path.Elem[i].Name {
+		if s.id != path.Elem[i].GetName() {
 			return false
 		}
 	}
@@ -224,20 +246,24 @@ func (pi *pathInfo) equalsPathNoKeys(path *gnmi.Path) bool {
 }
 
 func (pi *pathInfo) isSubPathOf(path *pathInfo) bool {
+	return pi.isPrefixOf(path.origin, path.segments)
+}
+
+func (pi *pathInfo) isPrefixOf(origin string, segments []segment) bool {
 	// If both set an origin it has to match. Otherwise we ignore the origin
-	if pi.origin != "" && path.origin != "" && pi.origin != path.origin {
+	if pi.origin != "" && origin != "" && pi.origin != origin {
 		return false
 	}
 
 	// The "parent" path should have the same length or be shorter than the
 	// sub-path to have a chance to match
-	if len(pi.segments) > len(path.segments) {
+	if len(pi.segments) > len(segments) {
 		return false
 	}
 
 	// Compare the elements and exit if we find a mismatch
 	for i, p := range pi.segments {
-		ps := path.segments[i]
+		ps := segments[i]
 		if p.namespace != "" && ps.namespace != "" && p.namespace != ps.namespace {
 			return false
 		}
@@ -369,24 +395,52 @@ func (pi *pathInfo) String() string {
 	return path
 }
 
-func (pi *pathInfo) tags(pathPrefix bool) map[string]string {
-	tags := make(map[string]string, len(pi.keyValues))
+type pathKey struct {
+	segment keySegment
+	name    string
+	tag     string
+	value   string
+}
+
+// keys returns the keys of all path elements together with the tag-name
+// to use for them in the order of the path elements.
+func (pi *pathInfo) keys(pathPrefix bool) []pathKey {
+	keys := make([]pathKey, 0, len(pi.keyValues))
+	seen := make(map[string]bool, len(pi.keyValues))
 	for _, s := range pi.keyValues {
 		var prefix string
 		if pathPrefix && s.name != "" {
 			prefix = s.name + "_"
 		}
-		for k, v := range s.kv {
+
+		// Sort the keys to get a deterministic order
+		names := make([]string, 0, len(s.kv))
+		for k := range s.kv {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
 			key := strings.ReplaceAll(prefix+k, "-", "_")
 
 			// Use short-form of key if possible
-			if _, exists := tags[key]; !exists {
-				tags[key] = v
-				continue
+			if seen[key] {
+				key = s.path + "/" + key
 			}
-			tags[s.path+"/"+key] = v
+			seen[key] = true
+			keys = append(keys, pathKey{segment: s, name: k, tag: key, value: s.kv[k]})
 		}
 	}
 
+	return keys
+}
+
+func (pi *pathInfo) tags(pathPrefix bool) map[string]string {
+	keys := pi.keys(pathPrefix)
+	tags := make(map[string]string, len(keys))
+	for _, k := range keys {
+		tags[k.tag] = k.value
+	}
+
 	return tags
 }
@@ -0,0 +1,80 @@
+package gnmi
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// State of a single device shared between its subscription goroutine and
+// the reporting
+type targetState struct {
+	address    string
+	host       string
+	port       string
+	connected  atomic.Bool
+	lastUpdate atomic.Int64
+
+	// Time of the last update per subscription name, the map itself is not
+	// modified after creation so only the values need to be synchronized.
+	subscriptions map[string]*atomic.Int64
+
+	// Ring buffer with the most recent connection errors, only written on
+	// the error path
+	errors     [maxTargetErrors]targetError
+	errorCount int
+	errLock    sync.Mutex
+}
+
+// Number of recent errors kept per device
+const maxTargetErrors = 10
+
+type targetError struct {
+	class   string
+	message string
+	ts      time.Time
+}
+
+func newTargetState(address string, subscriptions []string) (*targetState, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+	t := &targetState{
+		address:       address,
+		host:          host,
+		port:          port,
+		subscriptions: make(map[string]*atomic.Int64, len(subscriptions)),
+	}
+	for _, name := range subscriptions {
+		t.subscriptions[name] = &atomic.Int64{}
+	}
+	return t, nil
+}
+
+func (t *targetState) updated(subscription string, ts time.Time) {
+	if last, found := t.subscriptions[subscription]; found {
+		last.Store(ts.UnixNano())
+	}
+}
+
+func (t *targetState) failed(class string, err error) {
+	t.errLock.Lock()
+	defer t.errLock.Unlock()
+	t.errors[t.errorCount%maxTargetErrors] = targetError{class: class, message: err.Error(), ts: time.Now()}
+	t.errorCount++
+}
+
+// Get the most recent errors, newest first
+func (t *targetState) recentErrors() []targetError {
+	t.errLock.Lock()
+	defer t.errLock.Unlock()
+	n := min(t.errorCount, maxTargetErrors)
+	errs := make([]targetError, 0, n)
+	for i := 1; i <= n; i++ {
+		errs = append(errs, t.errors[(t.errorCount-i)%maxTargetErrors])
+	}
+	return errs
+}